@@ -0,0 +1,170 @@
+package deej
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	auditLogFilename   = "audit.jsonl"
+	auditLogMaxBytes   = 5 * 1024 * 1024
+	auditLogMaxBackups = 5
+)
+
+// AuditLogger appends a JSON-lines record of every command execution under
+// logDirectory, rotating by size (5MB across up to 5 backup files) so it never
+// grows unbounded. It's the durable, structured counterpart to the in-memory log
+// ring buffer and status server: where those answer "what's happening right now",
+// this answers "why did my slider launch a browser at 3am" days later.
+type AuditLogger struct {
+	logger *zap.SugaredLogger
+
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	curBytes int64
+}
+
+// NewAuditLogger creates an AuditLogger writing to logDirectory/audit.jsonl,
+// creating the directory and file if they don't already exist.
+func NewAuditLogger(logger *zap.SugaredLogger) (*AuditLogger, error) {
+	al := &AuditLogger{
+		logger: logger.Named("audit_log"),
+		path:   filepath.Join(logDirectory, auditLogFilename),
+	}
+
+	if err := al.openLocked(); err != nil {
+		return nil, err
+	}
+
+	return al, nil
+}
+
+func (al *AuditLogger) openLocked() error {
+	if err := os.MkdirAll(logDirectory, 0o755); err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(al.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat audit log: %w", err)
+	}
+
+	al.file = file
+	al.curBytes = info.Size()
+
+	return nil
+}
+
+// Record appends a single command execution to the audit log as one JSON line,
+// rotating the file first if appending would push it past its size cap.
+func (al *AuditLogger) Record(execution CommandExecution) {
+	line, err := json.Marshal(execution)
+	if err != nil {
+		al.logger.Warnw("Failed to marshal command execution for audit log", "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if al.curBytes+int64(len(line)) > auditLogMaxBytes {
+		if err := al.rotateLocked(); err != nil {
+			al.logger.Warnw("Failed to rotate audit log", "error", err)
+		}
+	}
+
+	if al.file == nil {
+		return
+	}
+
+	n, err := al.file.Write(line)
+	if err != nil {
+		al.logger.Warnw("Failed to write to audit log", "error", err)
+		return
+	}
+
+	al.curBytes += int64(n)
+}
+
+// rotateLocked shifts audit.jsonl.N -> audit.jsonl.N+1 (dropping anything past
+// auditLogMaxBackups), moves the current file to audit.jsonl.1, and opens a fresh
+// one in its place. Caller must hold al.mu.
+func (al *AuditLogger) rotateLocked() error {
+	if al.file != nil {
+		al.file.Close()
+		al.file = nil
+	}
+
+	for i := auditLogMaxBackups - 1; i >= 1; i-- {
+		src := al.backupPath(i)
+		dst := al.backupPath(i + 1)
+
+		if _, err := os.Stat(src); err == nil {
+			os.Rename(src, dst)
+		}
+	}
+
+	if _, err := os.Stat(al.path); err == nil {
+		if err := os.Rename(al.path, al.backupPath(1)); err != nil {
+			return fmt.Errorf("rotate current audit log: %w", err)
+		}
+	}
+
+	return al.openLocked()
+}
+
+func (al *AuditLogger) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", al.path, n)
+}
+
+// Tail returns up to n of the most recent audit log entries from the active file
+// (newest last), for display through the status server. It doesn't reach into
+// rotated backups - those are for manual/offline inspection.
+func (al *AuditLogger) Tail(n int) []CommandExecution {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	file, err := os.Open(al.path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	result := make([]CommandExecution, 0, len(lines))
+	for _, line := range lines {
+		var execution CommandExecution
+		if err := json.Unmarshal([]byte(line), &execution); err != nil {
+			continue
+		}
+
+		result = append(result, execution)
+	}
+
+	return result
+}
@@ -0,0 +1,37 @@
+package deej
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestAuditLoggerRotatesAndTailsTheActiveFile(t *testing.T) {
+	al := &AuditLogger{
+		logger: zap.NewNop().Sugar(),
+		path:   filepath.Join(t.TempDir(), auditLogFilename),
+	}
+
+	if err := al.openLocked(); err != nil {
+		t.Fatalf("openLocked: %v", err)
+	}
+
+	// write enough executions to push the active file past auditLogMaxBytes at
+	// least once
+	bigOutput := strings.Repeat("x", 1024)
+	for i := 0; i < 6000; i++ {
+		al.Record(CommandExecution{Index: 0, Output: bigOutput})
+	}
+
+	if _, err := os.Stat(al.path + ".1"); err != nil {
+		t.Fatalf("expected a rotated backup file to exist: %v", err)
+	}
+
+	tail := al.Tail(1)
+	if len(tail) != 1 {
+		t.Fatalf("expected Tail(1) to return exactly one entry, got %d", len(tail))
+	}
+}
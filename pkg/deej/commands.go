@@ -1,24 +1,154 @@
 package deej
 
 import (
+	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/omriharel/deej/pkg/deej/util"
 )
 
-// RunConfiguredCommand executes the command configured for the given index, if any.
+// OnRetrigger describes what a CommandRunner should do when a slider crosses a
+// command's trigger condition again while a previous invocation for the same index
+// is still running.
+type OnRetrigger string
+
+const (
+	// OnRetriggerQueue waits for a running slot to free up, then runs.
+	OnRetriggerQueue OnRetrigger = "queue"
+
+	// OnRetriggerIgnore silently drops the new invocation.
+	OnRetriggerIgnore OnRetrigger = "ignore"
+
+	// OnRetriggerReplace kills the oldest running invocation to make room.
+	OnRetriggerReplace OnRetrigger = "replace"
+)
+
+const defaultMaxConcurrent = 1
+
+// processGroupHandle lets CommandRunner kill a configured command's entire
+// descendant tree, not just the directly-spawned process: a process group and
+// SIGKILL on Unix, a Job Object on Windows.
+type processGroupHandle interface {
+	Kill() error
+	Release()
+}
+
+// CommandExecution records a single invocation of a configured command, for
+// display through the status server and the audit log.
+type CommandExecution struct {
+	Index         int           `json:"index"`
+	TriggerReason string        `json:"triggerReason"`
+	ShellWrapped  bool          `json:"shellWrapped"`
+	Command       string        `json:"command"`
+	Args          []string      `json:"args"`
+	PID           int           `json:"pid"`
+	Started       time.Time     `json:"started"`
+	Finished      time.Time     `json:"finished,omitempty"`
+	Duration      time.Duration `json:"duration,omitempty"`
+	ExitCode      int           `json:"exitCode"`
+	Running       bool          `json:"running"`
+	TimedOut      bool          `json:"timedOut,omitempty"`
+	Output        string        `json:"output,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+type runningCommand struct {
+	execution *CommandExecution
+	process   processGroupHandle
+	done      chan struct{}
+	closeDone sync.Once
+}
+
+type indexRegistry struct {
+	mu            sync.Mutex
+	cond          *sync.Cond
+	maxConcurrent int
+	live          []*runningCommand
+}
+
+// CommandRunner supervises every configured command execution: it captures stdout
+// and stderr into the shared log ring buffer, enforces each index's Timeout,
+// MaxConcurrent and OnRetrigger policy, and keeps a registry of live children so a
+// config reload or shutdown can terminate them cleanly.
+type CommandRunner struct {
+	deej     *Deej
+	logger   *zap.SugaredLogger
+	logs     *logRingBuffer
+	auditLog *AuditLogger
+
+	registries sync.Map // map[commandKey]*indexRegistry
+
+	historyMu sync.Mutex
+	history   []CommandExecution
+}
+
+const maxExecutionHistory = 200
+
+// NewCommandRunner creates a CommandRunner for the given Deej instance, capturing
+// command output into the provided log ring buffer and appending a structured
+// record of every execution to auditLog. auditLog may be nil, in which case only
+// the in-memory history (exposed via RecentExecutions) is kept.
+func NewCommandRunner(d *Deej, logs *logRingBuffer, auditLog *AuditLogger) *CommandRunner {
+	return &CommandRunner{
+		deej:     d,
+		logger:   d.logger.Named("command_runner"),
+		logs:     logs,
+		auditLog: auditLog,
+	}
+}
+
+// RunConfiguredCommand executes the command configured for the given index, if any,
+// through d's CommandRunner. It's kept as a thin pass-through so existing call
+// sites that trigger commands off a Deej instance don't need to know about
+// CommandRunner directly.
 func (d *Deej) RunConfiguredCommand(index int) {
-	logger := d.logger.Named("commands")
+	d.commandRunner.RunConfiguredCommand(index)
+}
 
-	spec, ok := d.config.Commands[index]
-	if !ok || len(spec.Args) == 0 {
-		if d.Verbose() {
-			logger.Debugw("No command configured for index", "index", index)
+// RunConfiguredCommand fires every command attached to the given index that has no
+// Trigger of its own - i.e. it preserves the pre-trigger behavior where the command
+// fires unconditionally whenever this is called. Commands with a Trigger are fired
+// by the trigger evaluator in triggers.go instead, off of slider value updates.
+func (r *CommandRunner) RunConfiguredCommand(index int) {
+	specs, ok := r.deej.config.Commands[index]
+	if !ok || len(specs) == 0 {
+		if r.deej.Verbose() {
+			r.logger.Debugw("No command configured for index", "index", index)
 		}
 		return
 	}
 
+	for specIdx, spec := range specs {
+		if spec.Trigger != nil {
+			continue
+		}
+
+		r.fire(index, specIdx, spec, "manual")
+	}
+}
+
+// commandKey identifies one of potentially several CommandSpecs attached to a
+// single slider index, for registry/concurrency-tracking purposes.
+type commandKey struct {
+	index   int
+	specIdx int
+}
+
+// fire builds the final argv for spec (applying shell-wrapping if configured) and,
+// subject to its concurrency/retrigger policy, spawns it. reason records why this
+// invocation happened ("manual", "rising", "falling" or "mute_toggle"), for the
+// status server and audit log.
+func (r *CommandRunner) fire(index, specIdx int, spec CommandSpec, reason string) {
+	if len(spec.Args) == 0 {
+		return
+	}
+
 	args := append([]string(nil), spec.Args...)
 
 	if spec.Shell {
@@ -31,34 +161,302 @@ func (d *Deej) RunConfiguredCommand(index int) {
 	}
 
 	if len(args) == 0 {
-		if d.Verbose() {
-			logger.Debugw("Command payload empty after processing", "index", index)
+		if r.deej.Verbose() {
+			r.logger.Debugw("Command payload empty after processing", "index", index)
 		}
 		return
 	}
 
-	command := args[0]
-	commandArgs := append([]string(nil), args[1:]...)
+	key := commandKey{index: index, specIdx: specIdx}
+	registry := r.registryFor(key, spec)
+
+	go r.runWhenReady(index, spec, args, reason, registry)
+}
+
+// Shutdown kills every currently running command across all indices. It's called
+// when deej exits or when the config is reloaded, so stale children don't outlive
+// the configuration that spawned them.
+func (r *CommandRunner) Shutdown() {
+	r.registries.Range(func(_, value interface{}) bool {
+		registry := value.(*indexRegistry)
+
+		registry.mu.Lock()
+		for _, rc := range registry.live {
+			r.killLocked(rc)
+		}
+		registry.mu.Unlock()
+
+		return true
+	})
+}
+
+// RecentExecutions returns the most recent command executions (newest last), for
+// display through the status server.
+func (r *CommandRunner) RecentExecutions() []CommandExecution {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	result := make([]CommandExecution, len(r.history))
+	copy(result, r.history)
+
+	return result
+}
+
+func (r *CommandRunner) registryFor(key commandKey, spec CommandSpec) *indexRegistry {
+	maxConcurrent := spec.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrent
+	}
 
-	go func(cmdName string, cmdArgs []string) {
-		cmd := exec.Command(cmdName, cmdArgs...)
+	value, _ := r.registries.LoadOrStore(key, &indexRegistry{maxConcurrent: maxConcurrent})
+	registry := value.(*indexRegistry)
+
+	registry.mu.Lock()
+	if registry.cond == nil {
+		registry.cond = sync.NewCond(&registry.mu)
+	}
+	registry.maxConcurrent = maxConcurrent
+	registry.mu.Unlock()
+
+	return registry
+}
+
+// runWhenReady applies the index's OnRetrigger policy and, once a slot is
+// available, spawns the command.
+func (r *CommandRunner) runWhenReady(index int, spec CommandSpec, args []string, reason string, registry *indexRegistry) {
+	registry.mu.Lock()
+
+	for len(registry.live) >= registry.maxConcurrent {
+		switch spec.OnRetrigger {
+		case OnRetriggerReplace:
+			r.killLocked(registry.live[0])
+			// killLocked signals completion asynchronously, so wait for the slot
+			// to actually free up rather than assuming it already has.
+			registry.cond.Wait()
+
+		case OnRetriggerIgnore, "":
+			registry.mu.Unlock()
+
+			if r.deej.Verbose() {
+				r.logger.Debugw("Ignoring retrigger, command already running", "index", index)
+			}
 
-		if err := cmd.Start(); err != nil {
-			logger.Warnw("Failed to execute configured command", "index", index, "command", cmdName, "args", cmdArgs, "error", err)
 			return
+
+		default: // OnRetriggerQueue
+			registry.cond.Wait()
 		}
+	}
 
-		if d.Verbose() {
-			logger.Debugw("Started configured command", "index", index, "command", cmdName, "args", cmdArgs)
+	rc := &runningCommand{done: make(chan struct{})}
+	registry.live = append(registry.live, rc)
+	registry.mu.Unlock()
+
+	r.spawn(index, spec, args, reason, registry, rc)
+}
+
+func (r *CommandRunner) spawn(index int, spec CommandSpec, args []string, reason string, registry *indexRegistry, rc *runningCommand) {
+	defer r.finish(registry, rc)
+
+	command := args[0]
+	commandArgs := args[1:]
+
+	cmd := exec.Command(command, commandArgs...)
+	prepareCommandProcAttr(cmd)
+
+	tag := fmt.Sprintf("command[%d]", index)
+	capture := &capturedOutput{}
+	cmd.Stdout = io.MultiWriter(&taggedLogWriter{tag: tag, stream: "stdout", logs: r.logs}, capture)
+	cmd.Stderr = io.MultiWriter(&taggedLogWriter{tag: tag, stream: "stderr", logs: r.logs}, capture)
+
+	execution := &CommandExecution{
+		Index:         index,
+		TriggerReason: reason,
+		ShellWrapped:  spec.Shell,
+		Command:       command,
+		Args:          commandArgs,
+		Started:       time.Now(),
+		Running:       true,
+	}
+	rc.execution = execution
+
+	if err := cmd.Start(); err != nil {
+		r.logger.Warnw("Failed to execute configured command", "index", index, "command", command, "args", commandArgs, "error", err)
+
+		execution.Running = false
+		execution.Finished = time.Now()
+		execution.Duration = execution.Finished.Sub(execution.Started)
+		execution.Error = err.Error()
+		r.record(*execution)
+
+		if r.auditLog != nil {
+			r.auditLog.Record(*execution)
 		}
 
-		if err := cmd.Wait(); err != nil {
-			logger.Warnw("Configured command exited with error", "index", index, "command", cmdName, "args", cmdArgs, "error", err)
-			return
+		return
+	}
+
+	execution.PID = cmd.Process.Pid
+
+	process, err := newProcessGroupHandle(cmd)
+	if err != nil {
+		r.logger.Warnw("Failed to set up process group for configured command, kill-on-retrigger/timeout/shutdown won't terminate it",
+			"index", index, "command", command, "error", err)
+	} else {
+		rc.process = process
+		defer process.Release()
+	}
+
+	if r.deej.Verbose() {
+		r.logger.Debugw("Started configured command", "index", index, "command", command, "args", commandArgs, "pid", execution.PID)
+	}
+
+	waitDone := make(chan error, 1)
+	go func() { waitDone <- cmd.Wait() }()
+
+	var timeoutChannel <-chan time.Time
+	if spec.Timeout > 0 {
+		timer := time.NewTimer(spec.Timeout)
+		defer timer.Stop()
+		timeoutChannel = timer.C
+	}
+
+	select {
+	case err := <-waitDone:
+		execution.Running = false
+		execution.Finished = time.Now()
+		execution.ExitCode = cmd.ProcessState.ExitCode()
+
+		if err != nil {
+			execution.Error = err.Error()
+			r.logger.Warnw("Configured command exited with error", "index", index, "command", command, "error", err)
+		} else if r.deej.Verbose() {
+			r.logger.Debugw("Configured command finished successfully", "index", index, "command", command)
+		}
+
+	case <-rc.done:
+		// killed out from under us by Shutdown/replace before it exited on its own
+		<-waitDone
+
+		execution.Running = false
+		execution.Finished = time.Now()
+		execution.ExitCode = cmd.ProcessState.ExitCode()
+		execution.Error = "killed"
+
+	case <-timeoutChannel:
+		r.logger.Warnw("Configured command exceeded its timeout, killing", "index", index, "command", command, "timeout", spec.Timeout)
+
+		r.kill(rc)
+		<-waitDone
+
+		execution.Running = false
+		execution.Finished = time.Now()
+		execution.ExitCode = cmd.ProcessState.ExitCode()
+		execution.TimedOut = true
+		execution.Error = "timed out"
+	}
+
+	execution.Duration = execution.Finished.Sub(execution.Started)
+	execution.Output = capture.String()
+
+	r.record(*execution)
+
+	if r.auditLog != nil {
+		r.auditLog.Record(*execution)
+	}
+}
+
+func (r *CommandRunner) finish(registry *indexRegistry, rc *runningCommand) {
+	registry.mu.Lock()
+	for i, candidate := range registry.live {
+		if candidate == rc {
+			registry.live = append(registry.live[:i], registry.live[i+1:]...)
+			break
 		}
+	}
+	registry.cond.Broadcast()
+	registry.mu.Unlock()
+}
 
-		if d.Verbose() {
-			logger.Debugw("Configured command finished successfully", "index", index, "command", cmdName)
+// kill terminates a running command's entire process group/job object, closing
+// its done channel so whichever goroutine is waiting on it can unblock. It may be
+// called concurrently for the same runningCommand - e.g. a Timeout elapsing at the
+// same moment Shutdown or an OnRetriggerReplace fires on the same index - so the
+// close itself is guarded by closeDone rather than a check-then-act select.
+func (r *CommandRunner) kill(rc *runningCommand) {
+	if rc.process != nil {
+		if err := rc.process.Kill(); err != nil {
+			r.logger.Warnw("Failed to kill configured command", "error", err)
 		}
-	}(command, commandArgs)
+	}
+
+	rc.closeDone.Do(func() {
+		close(rc.done)
+	})
+}
+
+// killLocked is kill, called with registry.mu already held (e.g. from the
+// OnRetrigger=replace path or Shutdown).
+func (r *CommandRunner) killLocked(rc *runningCommand) {
+	r.kill(rc)
+}
+
+func (r *CommandRunner) record(execution CommandExecution) {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	r.history = append(r.history, execution)
+	if len(r.history) > maxExecutionHistory {
+		r.history = r.history[len(r.history)-maxExecutionHistory:]
+	}
+}
+
+// taggedLogWriter adapts a child process's stdout/stderr into the shared log ring
+// buffer, tagging each line with the index and stream it came from.
+type taggedLogWriter struct {
+	tag    string
+	stream string
+	logs   *logRingBuffer
+}
+
+func (w *taggedLogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+
+		w.logs.Write([]byte(fmt.Sprintf("[%s/%s] %s", w.tag, w.stream, line)))
+	}
+
+	return len(p), nil
+}
+
+const maxCapturedOutputBytes = 4096
+
+// capturedOutput keeps the last maxCapturedOutputBytes of a command's combined
+// stdout/stderr, for CommandExecution.Output. It's intentionally much smaller than
+// the full ring buffer capture: the audit log and status server only need enough to
+// see what a command printed, not a full transcript.
+type capturedOutput struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (c *capturedOutput) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.buf = append(c.buf, p...)
+	if len(c.buf) > maxCapturedOutputBytes {
+		c.buf = c.buf[len(c.buf)-maxCapturedOutputBytes:]
+	}
+
+	return len(p), nil
+}
+
+func (c *capturedOutput) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return string(c.buf)
 }
@@ -0,0 +1,32 @@
+//go:build !windows
+
+package deej
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// prepareCommandProcAttr puts a configured command in its own process group, so
+// killing it later (via newProcessGroupHandle) reliably takes its whole descendant
+// tree down with it, rather than just the directly-spawned shell.
+func prepareCommandProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+type unixProcessGroup struct {
+	pid int
+}
+
+// newProcessGroupHandle must be called after cmd.Start(), once cmd.Process.Pid is
+// valid.
+func newProcessGroupHandle(cmd *exec.Cmd) (processGroupHandle, error) {
+	return &unixProcessGroup{pid: cmd.Process.Pid}, nil
+}
+
+func (g *unixProcessGroup) Kill() error {
+	// negative pid targets the whole process group we set up via Setpgid
+	return syscall.Kill(-g.pid, syscall.SIGKILL)
+}
+
+func (g *unixProcessGroup) Release() {}
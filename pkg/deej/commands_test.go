@@ -0,0 +1,31 @@
+package deej
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestKillIsIdempotentUnderConcurrency guards against the close-of-closed-channel
+// panic that used to be possible when a command's Timeout fired at the same
+// moment a config reload (Shutdown) or an OnRetriggerReplace on the same index
+// called kill() for the same runningCommand.
+func TestKillIsIdempotentUnderConcurrency(t *testing.T) {
+	r := &CommandRunner{}
+	rc := &runningCommand{done: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.kill(rc)
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-rc.done:
+	default:
+		t.Fatalf("expected rc.done to be closed after kill")
+	}
+}
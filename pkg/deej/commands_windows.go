@@ -0,0 +1,68 @@
+//go:build windows
+
+package deej
+
+import (
+	"fmt"
+	"os/exec"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// prepareCommandProcAttr is a no-op on Windows: job object assignment has to
+// happen after Start(), once we have a real process handle, so it's done in
+// newProcessGroupHandle instead.
+func prepareCommandProcAttr(cmd *exec.Cmd) {}
+
+// commandJobObject wraps a Windows Job Object configured to kill every process it
+// contains as soon as the job handle is closed, giving us the Windows equivalent of
+// "kill(-pgid)" on Unix.
+type commandJobObject struct {
+	handle windows.Handle
+}
+
+func newProcessGroupHandle(cmd *exec.Cmd) (processGroupHandle, error) {
+	handle, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create job object: %w", err)
+	}
+
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+
+	if _, err := windows.SetInformationJobObject(
+		handle,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info)),
+	); err != nil {
+		windows.CloseHandle(handle)
+		return nil, fmt.Errorf("set job object limits: %w", err)
+	}
+
+	processHandle, err := windows.OpenProcess(windows.PROCESS_ALL_ACCESS, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, fmt.Errorf("open process: %w", err)
+	}
+	defer windows.CloseHandle(processHandle)
+
+	if err := windows.AssignProcessToJobObject(handle, processHandle); err != nil {
+		windows.CloseHandle(handle)
+		return nil, fmt.Errorf("assign process to job object: %w", err)
+	}
+
+	return &commandJobObject{handle: handle}, nil
+}
+
+func (j *commandJobObject) Kill() error {
+	return windows.TerminateJobObject(j.handle, 1)
+}
+
+func (j *commandJobObject) Release() {
+	windows.CloseHandle(j.handle)
+}
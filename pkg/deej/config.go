@@ -1,6 +1,8 @@
 package deej
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"path"
 	"strconv"
@@ -22,8 +24,92 @@ type SliderColorConfig struct {
 }
 
 type CommandSpec struct {
-	Args  []string
-	Shell bool
+	Args  []string `json:"args"`
+	Shell bool     `json:"shell"`
+
+	// Timeout kills the command if it's still running after this long. Zero means
+	// no timeout.
+	Timeout time.Duration `json:"timeout"`
+
+	// MaxConcurrent caps how many invocations of this index's command may run at
+	// once. Zero/unset falls back to 1.
+	MaxConcurrent int `json:"maxConcurrent"`
+
+	// OnRetrigger controls what happens when the index is triggered again while
+	// MaxConcurrent invocations are already running. Zero/unset falls back to
+	// OnRetriggerIgnore.
+	OnRetrigger OnRetrigger `json:"onRetrigger"`
+
+	// Trigger describes the slider condition that fires this command. A nil
+	// Trigger preserves the original behavior: the command fires unconditionally
+	// whenever RunConfiguredCommand(index) is invoked, with no notion of edges or
+	// thresholds.
+	Trigger *Trigger `json:"trigger,omitempty"`
+}
+
+// TriggerCondition selects which direction of a threshold crossing a Trigger cares
+// about.
+type TriggerCondition string
+
+const (
+	// TriggerOnRising fires when the slider value crosses the threshold moving up.
+	TriggerOnRising TriggerCondition = "rising"
+
+	// TriggerOnFalling fires when the slider value crosses the threshold moving down.
+	TriggerOnFalling TriggerCondition = "falling"
+
+	// TriggerOnAny fires on either direction.
+	TriggerOnAny TriggerCondition = "any"
+)
+
+// Trigger describes a condition on a single slider index that fires a command.
+// Several commands with different Triggers can be attached to the same index.
+type Trigger struct {
+	// On selects which crossing direction(s) fire the command. Defaults to
+	// TriggerOnAny.
+	On TriggerCondition `json:"on"`
+
+	// Threshold is the slider value (0.0..1.0) the trigger watches for a crossing
+	// of.
+	Threshold float32 `json:"threshold"`
+
+	// Deadband adds hysteresis around Threshold, so jitter right at the threshold
+	// doesn't repeatedly re-fire the trigger: the slider must reach
+	// Threshold+Deadband/2 to count as having risen past it, and come back down
+	// to Threshold-Deadband/2 to count as having fallen back.
+	Deadband float32 `json:"deadband"`
+
+	// MuteToggle additionally fires the command whenever the slider crosses into
+	// its very-low or very-high extreme, regardless of Threshold. Leaving an
+	// extreme doesn't fire it.
+	MuteToggle bool `json:"muteToggle"`
+
+	// HoldMs requires the slider to remain past the threshold for this long
+	// before the command fires, filtering out quick flicks.
+	HoldMs int `json:"holdMs"`
+}
+
+// StatusServerConfig controls the opt-in local HTTP status/telemetry server.
+// It's always bound to 127.0.0.1 regardless of the configured port, so it can
+// never be reached from outside the machine deej is running on.
+type StatusServerConfig struct {
+	Enabled bool
+	Port    int
+}
+
+// ControlAPIConfig controls the opt-in local HTTP/JSON API used to read and set
+// volumes and observe slider activity. Like the status server it's always bound to
+// 127.0.0.1, and every request must additionally present Token.
+type ControlAPIConfig struct {
+	Enabled bool
+	Port    int
+
+	// Token is required on every request (as an "Authorization: Bearer <token>"
+	// header). It lives in preferences.yaml, not config.yaml, since it's a
+	// machine-local secret rather than something meant to be shared/versioned. If
+	// the API is enabled and no token is set, one is generated and persisted on
+	// first load.
+	Token string
 }
 
 type CanonicalConfig struct {
@@ -42,7 +128,24 @@ type CanonicalConfig struct {
 	SyncVolumes        bool
 	ColorMapping       map[int]SliderColorConfig
 	BackgroundLighting string
-	Commands           map[int]CommandSpec
+
+	// Commands maps a slider index to the list of commands attached to it. The
+	// shorthand forms are still accepted and each yield exactly one untriggered
+	// CommandSpec:
+	//   commands: { 3: "notify-send hello" }
+	//   commands: { 3: ["notify-send", "hello"] }
+	// The full form attaches several independently-triggered commands to the same
+	// index:
+	//   commands:
+	//     3:
+	//       - trigger: { on: rising, threshold: 0.8 }
+	//         args: ["notify-send", "loud!"]
+	//       - trigger: { on: falling, threshold: 0.2, hold_ms: 300 }
+	//         args: ["notify-send", "quiet"]
+	Commands map[int][]CommandSpec
+
+	StatusServer StatusServerConfig
+	ControlAPI   ControlAPIConfig
 
 	logger             *zap.SugaredLogger
 	notifier           Notifier
@@ -75,9 +178,19 @@ const (
 	configKeyColorMapping        = "color_mapping"
 	configKeyBackgroundLighting  = "background_lighting"
 	configKeyCommands            = "commands"
+	configKeyStatusServerEnabled = "status_server.enabled"
+	configKeyStatusServerPort    = "status_server.port"
+	configKeyControlAPIEnabled   = "control_api.enabled"
+	configKeyControlAPIPort      = "control_api.port"
+	configKeyControlAPIToken     = "control_api_token"
 
 	defaultCOMPort  = "COM4"
 	defaultBaudRate = 9600
+
+	defaultStatusServerPort = 13909
+	defaultControlAPIPort   = 13910
+
+	controlAPITokenBytes = 24
 )
 
 // has to be defined as a non-constant because we're using path.Join
@@ -116,6 +229,10 @@ func NewConfig(logger *zap.SugaredLogger, notifier Notifier) (*CanonicalConfig,
 	userConfig.SetDefault(configKeyColorMapping, map[string]map[string]string{})
 	userConfig.SetDefault(configKeyBackgroundLighting, "")
 	userConfig.SetDefault(configKeyCommands, map[string]interface{}{})
+	userConfig.SetDefault(configKeyStatusServerEnabled, false)
+	userConfig.SetDefault(configKeyStatusServerPort, defaultStatusServerPort)
+	userConfig.SetDefault(configKeyControlAPIEnabled, false)
+	userConfig.SetDefault(configKeyControlAPIPort, defaultControlAPIPort)
 
 	internalConfig := viper.New()
 	internalConfig.SetConfigName(internalConfigName)
@@ -270,6 +387,14 @@ func (cc *CanonicalConfig) populateFromVipers() error {
 	cc.ColorMapping = cc.parseColorMapping()
 	cc.BackgroundLighting = strings.TrimSpace(cc.userConfig.GetString(configKeyBackgroundLighting))
 	cc.Commands = cc.parseCommands()
+	cc.StatusServer = cc.parseStatusServerConfig()
+
+	controlAPI, err := cc.parseControlAPIConfig()
+	if err != nil {
+		cc.logger.Warnw("Failed to set up control API, leaving it disabled", "error", err)
+		controlAPI.Enabled = false
+	}
+	cc.ControlAPI = controlAPI
 
 	cc.logger.Debug("Populated config fields from vipers")
 
@@ -317,8 +442,69 @@ func (cc *CanonicalConfig) parseColorMapping() map[int]SliderColorConfig {
 	return result
 }
 
-func (cc *CanonicalConfig) parseCommands() map[int]CommandSpec {
-	result := make(map[int]CommandSpec)
+func (cc *CanonicalConfig) parseStatusServerConfig() StatusServerConfig {
+	port := cc.userConfig.GetInt(configKeyStatusServerPort)
+	if port <= 0 || port > 65535 {
+		cc.logger.Warnw("Invalid status server port specified, using default value",
+			"key", configKeyStatusServerPort,
+			"invalidValue", port,
+			"defaultValue", defaultStatusServerPort)
+
+		port = defaultStatusServerPort
+	}
+
+	return StatusServerConfig{
+		Enabled: cc.userConfig.GetBool(configKeyStatusServerEnabled),
+		Port:    port,
+	}
+}
+
+func (cc *CanonicalConfig) parseControlAPIConfig() (ControlAPIConfig, error) {
+	port := cc.userConfig.GetInt(configKeyControlAPIPort)
+	if port <= 0 || port > 65535 {
+		cc.logger.Warnw("Invalid control API port specified, using default value",
+			"key", configKeyControlAPIPort,
+			"invalidValue", port,
+			"defaultValue", defaultControlAPIPort)
+
+		port = defaultControlAPIPort
+	}
+
+	cfg := ControlAPIConfig{
+		Enabled: cc.userConfig.GetBool(configKeyControlAPIEnabled),
+		Port:    port,
+		Token:   strings.TrimSpace(cc.internalConfig.GetString(configKeyControlAPIToken)),
+	}
+
+	if cfg.Enabled && cfg.Token == "" {
+		token, err := generateControlAPIToken()
+		if err != nil {
+			return ControlAPIConfig{}, fmt.Errorf("generate control API token: %w", err)
+		}
+
+		cc.internalConfig.Set(configKeyControlAPIToken, token)
+		if err := cc.internalConfig.WriteConfigAs(path.Join(internalConfigPath, internalConfigFilepath)); err != nil {
+			return ControlAPIConfig{}, fmt.Errorf("persist generated control API token: %w", err)
+		}
+
+		cc.logger.Infow("Generated a new control API token", "path", path.Join(internalConfigPath, internalConfigFilepath))
+		cfg.Token = token
+	}
+
+	return cfg, nil
+}
+
+func generateControlAPIToken() (string, error) {
+	raw := make([]byte, controlAPITokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(raw), nil
+}
+
+func (cc *CanonicalConfig) parseCommands() map[int][]CommandSpec {
+	result := make(map[int][]CommandSpec)
 
 	raw := cc.userConfig.GetStringMap(configKeyCommands)
 	for key, value := range raw {
@@ -328,17 +514,78 @@ func (cc *CanonicalConfig) parseCommands() map[int]CommandSpec {
 			continue
 		}
 
-		spec, ok := cc.parseCommandValue(value)
+		specs, ok := cc.parseCommandEntry(value)
 		if !ok {
 			continue
 		}
 
-		result[sliderIdx] = spec
+		result[sliderIdx] = specs
 	}
 
 	return result
 }
 
+// parseCommandEntry parses one slider index's worth of YAML into the list of
+// CommandSpecs attached to it. This accepts the original shorthand forms (a bare
+// string, or an array of argv strings - both always yielding exactly one spec with
+// no trigger, i.e. "fire whenever RunConfiguredCommand(index) is called") as well as
+// the newer `[ {trigger: {...}, args: [...]}, ... ]` form that attaches several
+// independently-triggered commands to the same index.
+func (cc *CanonicalConfig) parseCommandEntry(value interface{}) ([]CommandSpec, bool) {
+	switch typed := value.(type) {
+	case []interface{}:
+		if len(typed) == 0 {
+			return nil, false
+		}
+
+		if _, isMapForm := typed[0].(map[string]interface{}); isMapForm {
+			specs := []CommandSpec{}
+			for _, rawEntry := range typed {
+				entry, ok := rawEntry.(map[string]interface{})
+				if !ok {
+					cc.logger.Warnw("Ignoring command entry with mixed array/object items", "value", rawEntry)
+					continue
+				}
+
+				spec, ok := cc.parseCommandMap(entry)
+				if !ok {
+					continue
+				}
+
+				specs = append(specs, spec)
+			}
+
+			if len(specs) == 0 {
+				return nil, false
+			}
+
+			return specs, true
+		}
+
+		// legacy shorthand: a flat argv array
+		spec, ok := cc.parseCommandValue(typed)
+		if !ok {
+			return nil, false
+		}
+
+		return []CommandSpec{spec}, true
+	case map[string]interface{}:
+		spec, ok := cc.parseCommandMap(typed)
+		if !ok {
+			return nil, false
+		}
+
+		return []CommandSpec{spec}, true
+	default:
+		spec, ok := cc.parseCommandValue(value)
+		if !ok {
+			return nil, false
+		}
+
+		return []CommandSpec{spec}, true
+	}
+}
+
 func (cc *CanonicalConfig) parseCommandValue(value interface{}) (CommandSpec, bool) {
 	switch typed := value.(type) {
 	case string:
@@ -451,5 +698,160 @@ func (cc *CanonicalConfig) parseCommandMap(value map[string]interface{}) (Comman
 		return CommandSpec{}, false
 	}
 
+	if timeoutValue, ok := value["timeout_ms"]; ok {
+		if timeoutMs, ok := toInt(timeoutValue); ok && timeoutMs > 0 {
+			spec.Timeout = time.Duration(timeoutMs) * time.Millisecond
+		} else {
+			cc.logger.Warnw("Ignoring non-positive-integer timeout_ms", "value", timeoutValue)
+		}
+	}
+
+	if maxConcurrentValue, ok := value["max_concurrent"]; ok {
+		if maxConcurrent, ok := toInt(maxConcurrentValue); ok && maxConcurrent > 0 {
+			spec.MaxConcurrent = maxConcurrent
+		} else {
+			cc.logger.Warnw("Ignoring non-positive-integer max_concurrent", "value", maxConcurrentValue)
+		}
+	}
+
+	if onRetriggerValue, ok := value["on_retrigger"]; ok {
+		if str, ok := onRetriggerValue.(string); ok {
+			switch OnRetrigger(strings.TrimSpace(str)) {
+			case OnRetriggerQueue:
+				spec.OnRetrigger = OnRetriggerQueue
+			case OnRetriggerIgnore:
+				spec.OnRetrigger = OnRetriggerIgnore
+			case OnRetriggerReplace:
+				spec.OnRetrigger = OnRetriggerReplace
+			default:
+				cc.logger.Warnw("Ignoring unrecognized on_retrigger value", "value", str)
+			}
+		} else {
+			cc.logger.Warnw("Ignoring non-string on_retrigger value", "value", onRetriggerValue)
+		}
+	}
+
+	if triggerValue, ok := value["trigger"]; ok {
+		triggerMap, ok := triggerValue.(map[string]interface{})
+		if !ok {
+			cc.logger.Warnw("Ignoring non-object trigger block", "value", triggerValue)
+			return spec, true
+		}
+
+		trigger, ok := cc.parseTrigger(triggerMap)
+		if !ok {
+			return CommandSpec{}, false
+		}
+
+		spec.Trigger = trigger
+	}
+
 	return spec, true
 }
+
+func (cc *CanonicalConfig) parseTrigger(value map[string]interface{}) (*Trigger, bool) {
+	trigger := &Trigger{On: TriggerOnAny}
+
+	if onValue, ok := value["on"]; ok {
+		str, ok := onValue.(string)
+		if !ok {
+			cc.logger.Warnw("Ignoring command entry with non-string trigger.on value", "value", onValue)
+			return nil, false
+		}
+
+		switch TriggerCondition(strings.TrimSpace(str)) {
+		case TriggerOnRising:
+			trigger.On = TriggerOnRising
+		case TriggerOnFalling:
+			trigger.On = TriggerOnFalling
+		case TriggerOnAny:
+			trigger.On = TriggerOnAny
+		default:
+			cc.logger.Warnw("Ignoring command entry with unrecognized trigger.on value", "value", str)
+			return nil, false
+		}
+	}
+
+	if thresholdValue, ok := value["threshold"]; ok {
+		threshold, ok := toFloat(thresholdValue)
+		if !ok {
+			cc.logger.Warnw("Ignoring command entry with non-numeric trigger.threshold value", "value", thresholdValue)
+			return nil, false
+		}
+
+		trigger.Threshold = clamp01(threshold)
+	}
+
+	if deadbandValue, ok := value["deadband"]; ok {
+		deadband, ok := toFloat(deadbandValue)
+		if !ok {
+			cc.logger.Warnw("Ignoring command entry with non-numeric trigger.deadband value", "value", deadbandValue)
+			return nil, false
+		}
+
+		trigger.Deadband = clamp01(deadband)
+	}
+
+	if muteToggleValue, ok := value["mute_toggle"]; ok {
+		muteToggle, ok := muteToggleValue.(bool)
+		if !ok {
+			cc.logger.Warnw("Ignoring command entry with non-bool trigger.mute_toggle value", "value", muteToggleValue)
+			return nil, false
+		}
+
+		trigger.MuteToggle = muteToggle
+	}
+
+	if holdMsValue, ok := value["hold_ms"]; ok {
+		holdMs, ok := toInt(holdMsValue)
+		if !ok || holdMs < 0 {
+			cc.logger.Warnw("Ignoring command entry with invalid trigger.hold_ms value", "value", holdMsValue)
+			return nil, false
+		}
+
+		trigger.HoldMs = holdMs
+	}
+
+	return trigger, true
+}
+
+func clamp01(v float32) float32 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	default:
+		return v
+	}
+}
+
+// toFloat accepts the handful of numeric types viper/YAML may hand us for a
+// fractional field and normalizes them to float32.
+func toFloat(value interface{}) (float32, bool) {
+	switch typed := value.(type) {
+	case float32:
+		return typed, true
+	case float64:
+		return float32(typed), true
+	case int:
+		return float32(typed), true
+	default:
+		return 0, false
+	}
+}
+
+// toInt accepts the handful of numeric types viper/YAML may hand us for an
+// integer-ish field and normalizes them to int.
+func toInt(value interface{}) (int, bool) {
+	switch typed := value.(type) {
+	case int:
+		return typed, true
+	case int64:
+		return int(typed), true
+	case float64:
+		return int(typed), true
+	default:
+		return 0, false
+	}
+}
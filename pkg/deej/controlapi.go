@@ -0,0 +1,414 @@
+package deej
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sliderEvent is broadcast to /v1/events subscribers whenever a slider's value
+// changes.
+type sliderEvent struct {
+	Index int     `json:"index"`
+	Value float32 `json:"value"`
+}
+
+// volumeEvent is broadcast to /v1/events subscribers whenever a session's volume
+// is set through the control API (POST /v1/volumes/master or /v1/volumes/app).
+// Volume changes made by other means don't produce one.
+type volumeEvent struct {
+	Key    string  `json:"key"`
+	Volume float32 `json:"volume"`
+}
+
+// ControlAPIServer is a localhost-only, token-guarded HTTP/JSON API for reading and
+// setting volumes and observing slider activity, meant for external tools (stream
+// decks, macro pads, home automation) to integrate with deej without polling.
+//
+// It holds a single long-lived sessionFinder rather than building one per request,
+// since constructing one triggers a COM initialization on Windows that's wasteful
+// to repeat on every call.
+type ControlAPIServer struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	sfMu sync.Mutex
+	sf   sessionFinder
+
+	server *http.Server
+
+	slidersMu   sync.RWMutex
+	lastSliders []float32
+
+	subscribersMu sync.Mutex
+	subscribers   map[chan []byte]struct{}
+}
+
+// NewControlAPIServer creates a ControlAPIServer for the given Deej instance. It
+// does not start listening, or construct a session finder, until Start is called.
+func NewControlAPIServer(d *Deej) *ControlAPIServer {
+	return &ControlAPIServer{
+		deej:        d,
+		logger:      d.logger.Named("control_api"),
+		subscribers: map[chan []byte]struct{}{},
+	}
+}
+
+// Start opens the long-lived session finder and begins serving on
+// 127.0.0.1:<configured port>. It's a no-op if the control API isn't enabled.
+func (a *ControlAPIServer) Start() error {
+	if !a.deej.config.ControlAPI.Enabled {
+		return nil
+	}
+
+	sf, err := newSessionFinder(a.logger)
+	if err != nil {
+		return fmt.Errorf("create session finder: %w", err)
+	}
+	a.sf = sf
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/volumes/master", a.authenticated(a.handleMasterVolume))
+	mux.HandleFunc("/v1/volumes/app", a.authenticated(a.handleAppVolume))
+	mux.HandleFunc("/v1/sliders", a.authenticated(a.handleSliders))
+	mux.HandleFunc("/v1/events", a.authenticated(a.handleEvents))
+
+	addr := fmt.Sprintf("127.0.0.1:%d", a.deej.config.ControlAPI.Port)
+
+	a.server = &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	go func() {
+		a.logger.Infow("Starting control API server", "address", addr)
+
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			a.logger.Warnw("Control API server stopped unexpectedly", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop closes the control API server immediately, dropping any open connections
+// (including /v1/events streams), and releases its session finder, if it was
+// ever started.
+func (a *ControlAPIServer) Stop() {
+	if a.server != nil {
+		if err := a.server.Close(); err != nil {
+			a.logger.Warnw("Failed to close control API server cleanly", "error", err)
+		}
+	}
+
+	a.sfMu.Lock()
+	if a.sf != nil {
+		a.sf.Release()
+		a.sf = nil
+	}
+	a.sfMu.Unlock()
+}
+
+// NotifySliderValue records index's latest value and publishes a sliderEvent to
+// every /v1/events subscriber. It's meant to be called from the slider event loop
+// on every read, alongside HandleSliderValue/RunConfiguredCommand.
+func (a *ControlAPIServer) NotifySliderValue(index int, value float32) {
+	a.slidersMu.Lock()
+	for len(a.lastSliders) <= index {
+		a.lastSliders = append(a.lastSliders, 0)
+	}
+	a.lastSliders[index] = value
+	a.slidersMu.Unlock()
+
+	a.publish("slider", sliderEvent{Index: index, Value: value})
+}
+
+// NotifyVolumeChange publishes a volumeEvent to every /v1/events subscriber. It's
+// meant to be called whenever a session's volume changes, from any source.
+func (a *ControlAPIServer) NotifyVolumeChange(key string, volume float32) {
+	a.publish("volume", volumeEvent{Key: key, Volume: volume})
+}
+
+func (a *ControlAPIServer) publish(event string, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	message := []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, body))
+
+	a.subscribersMu.Lock()
+	defer a.subscribersMu.Unlock()
+
+	for ch := range a.subscribers {
+		select {
+		case ch <- message:
+		default:
+			// subscriber's too slow to keep up; drop the event rather than block
+		}
+	}
+}
+
+func (a *ControlAPIServer) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if token == "" || token != a.deej.config.ControlAPI.Token {
+			http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (a *ControlAPIServer) handleMasterVolume(w http.ResponseWriter, r *http.Request) {
+	matches := func(s session) bool { return s.Key() == masterSessionName }
+
+	switch r.Method {
+	case http.MethodGet:
+		volume, err := a.findSessionVolume(matches)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, map[string]float32{"volume": volume})
+
+	case http.MethodPost:
+		var body struct {
+			Volume float32 `json:"volume"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := a.setSessionVolume(matches, body.Volume); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		a.NotifyVolumeChange(masterSessionName, body.Volume)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *ControlAPIServer) handleAppVolume(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		matches, key, err := appSessionMatcher(r.URL.Query().Get("name"), r.URL.Query().Get("pid"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		volume, err := a.findSessionVolume(matches)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{"key": key, "volume": volume})
+
+	case http.MethodPost:
+		var body struct {
+			Name   string  `json:"name"`
+			PID    int     `json:"pid"`
+			Volume float32 `json:"volume"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		pidStr := ""
+		if body.PID != 0 {
+			pidStr = strconv.Itoa(body.PID)
+		}
+
+		matches, key, err := appSessionMatcher(body.Name, pidStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := a.setSessionVolume(matches, body.Volume); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		a.NotifyVolumeChange(key, body.Volume)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// appSessionMatcher builds a session-matching predicate out of a name (matched
+// case-insensitively against the session key, with or without ".exe") or a pid
+// (matched via matchSessionByPID, since pid lookup depends on the concrete,
+// platform-specific session type). Exactly one of name/pidStr should be non-empty.
+func appSessionMatcher(name, pidStr string) (func(session) bool, string, error) {
+	name = strings.TrimSpace(name)
+	pidStr = strings.TrimSpace(pidStr)
+
+	switch {
+	case name != "":
+		target := strings.ToLower(name)
+		alt := target
+		if !strings.HasSuffix(target, ".exe") {
+			alt = target + ".exe"
+		}
+
+		return func(s session) bool { return s.Key() == target || s.Key() == alt }, target, nil
+
+	case pidStr != "":
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid pid: %w", err)
+		}
+
+		return matchSessionByPID(pid), pidStr, nil
+
+	default:
+		return nil, "", fmt.Errorf("must specify either 'name' or 'pid'")
+	}
+}
+
+func (a *ControlAPIServer) handleSliders(w http.ResponseWriter, r *http.Request) {
+	a.slidersMu.RLock()
+	values := append([]float32(nil), a.lastSliders...)
+	a.slidersMu.RUnlock()
+
+	writeJSON(w, struct {
+		Values  []float32  `json:"values"`
+		Mapping *sliderMap `json:"mapping"`
+	}{
+		Values:  values,
+		Mapping: a.deej.config.SliderMapping,
+	})
+}
+
+func (a *ControlAPIServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan []byte, 16)
+
+	a.subscribersMu.Lock()
+	a.subscribers[ch] = struct{}{}
+	a.subscribersMu.Unlock()
+
+	defer func() {
+		a.subscribersMu.Lock()
+		delete(a.subscribers, ch)
+		a.subscribersMu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case message := <-ch:
+			if _, err := w.Write(message); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-time.After(30 * time.Second):
+			// keepalive comment, so intermediaries/clients don't time the connection out
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func (a *ControlAPIServer) findSessionVolume(matches func(session) bool) (float32, error) {
+	sessions, err := a.currentSessions()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range sessions {
+		if matches(s) {
+			return s.GetVolume(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("no matching audio session found")
+}
+
+func (a *ControlAPIServer) setSessionVolume(matches func(session) bool, volume float32) error {
+	sessions, err := a.currentSessions()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if !matches(s) {
+			continue
+		}
+
+		if err := s.SetVolume(volume); err != nil {
+			if err == errRefreshSessions {
+				a.refreshSessionFinder()
+				return fmt.Errorf("session expired, please retry")
+			}
+
+			return err
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("no matching audio session found")
+}
+
+func (a *ControlAPIServer) currentSessions() ([]session, error) {
+	a.sfMu.Lock()
+	sf := a.sf
+	a.sfMu.Unlock()
+
+	if sf == nil {
+		return nil, fmt.Errorf("control API session finder not initialized")
+	}
+
+	return sf.GetAllSessions()
+}
+
+func (a *ControlAPIServer) refreshSessionFinder() {
+	a.sfMu.Lock()
+	defer a.sfMu.Unlock()
+
+	if a.sf != nil {
+		a.sf.Release()
+	}
+
+	sf, err := newSessionFinder(a.logger)
+	if err != nil {
+		a.logger.Warnw("Failed to refresh control API session finder", "error", err)
+		a.sf = nil
+		return
+	}
+
+	a.sf = sf
+}
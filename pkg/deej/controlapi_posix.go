@@ -0,0 +1,9 @@
+//go:build !windows
+
+package deej
+
+// matchSessionByPID isn't implemented outside Windows yet - there's no posix
+// session type that exposes an OS pid the way wcaSession does.
+func matchSessionByPID(pid int) func(session) bool {
+	return func(s session) bool { return false }
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package deej
+
+// matchSessionByPID matches the session whose concrete type exposes the given
+// OS pid. Only wcaSession (Windows) exposes one.
+func matchSessionByPID(pid int) func(session) bool {
+	return func(s session) bool {
+		ws, ok := s.(*wcaSession)
+		return ok && int(ws.pid) == pid
+	}
+}
@@ -0,0 +1,111 @@
+package deej
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Deej is the top-level object tying configuration, session access and every
+// opt-in subsystem this series adds (the command runner and trigger evaluator,
+// the audit log, the status server and the control API) into a single
+// lifecycle: constructed once the config and notifier are ready, Start after
+// the session finder and slider reader are up, Stop on app exit.
+type Deej struct {
+	logger  *zap.SugaredLogger
+	config  *CanonicalConfig
+	verbose bool
+
+	logs *logRingBuffer
+
+	auditLog *AuditLogger
+
+	commandRunner    *CommandRunner
+	triggerEvaluator *TriggerEvaluator
+
+	statusServer *StatusServer
+	controlAPI   *ControlAPIServer
+}
+
+// NewDeej creates a Deej for the given config, mirroring logger's output into a
+// ring buffer the status server can serve. verbose mirrors whatever -v level the
+// caller parsed off the command line; Verbose() just reports it back.
+func NewDeej(logger *zap.SugaredLogger, config *CanonicalConfig, verbose bool) *Deej {
+	logs := newLogRingBuffer(logRingBufferDefaultMaxLines, logRingBufferDefaultMaxBytes)
+
+	mirrorCore := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		logs.asWriteSyncer(),
+		zapcore.DebugLevel,
+	)
+
+	mirroredLogger := logger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, mirrorCore)
+	})).Sugar()
+
+	d := &Deej{
+		logger:  mirroredLogger,
+		config:  config,
+		verbose: verbose,
+		logs:    logs,
+	}
+
+	auditLog, err := NewAuditLogger(d.logger)
+	if err != nil {
+		d.logger.Warnw("Failed to open audit log, command executions won't be persisted", "error", err)
+		auditLog = nil
+	}
+	d.auditLog = auditLog
+
+	d.commandRunner = NewCommandRunner(d, logs, auditLog)
+	d.triggerEvaluator = NewTriggerEvaluator(d.commandRunner)
+	d.statusServer = NewStatusServer(d, logs)
+	d.controlAPI = NewControlAPIServer(d)
+
+	return d
+}
+
+// Verbose reports whether verbose logging was requested (e.g. via a -v flag).
+func (d *Deej) Verbose() bool {
+	return d.verbose
+}
+
+// HandleSliderValue is the entry point the slider event loop calls on every
+// reported value. It lets several commands be attached to the same index with
+// different Trigger conditions (rising/falling/threshold/deadband/hold_ms/
+// mute_toggle), on top of the unconditional RunConfiguredCommand path.
+func (d *Deej) HandleSliderValue(index int, value float32) {
+	d.triggerEvaluator.HandleSliderValue(index, value)
+	d.controlAPI.NotifySliderValue(index, value)
+}
+
+// Start begins every opt-in subsystem this Deej owns that's enabled in config,
+// and starts watching for config reloads so in-flight commands started under a
+// since-replaced config get killed rather than left to run against stale specs.
+func (d *Deej) Start() error {
+	if err := d.statusServer.Start(); err != nil {
+		return fmt.Errorf("start status server: %w", err)
+	}
+
+	if err := d.controlAPI.Start(); err != nil {
+		return fmt.Errorf("start control API server: %w", err)
+	}
+
+	reloaded := d.config.SubscribeToChanges()
+	go func() {
+		for range reloaded {
+			d.logger.Debug("Config reloaded, shutting down commands started under the old config")
+			d.commandRunner.Shutdown()
+		}
+	}()
+
+	return nil
+}
+
+// Stop shuts down every subsystem Start began and kills any commands still running.
+func (d *Deej) Stop() {
+	d.controlAPI.Stop()
+	d.statusServer.Stop()
+	d.commandRunner.Shutdown()
+}
@@ -0,0 +1,105 @@
+package deej
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// logRingBufferEntry is a single captured log line along with the time it was received.
+type logRingBufferEntry struct {
+	Time time.Time `json:"time"`
+	Line string    `json:"line"`
+}
+
+// logRingBuffer mirrors log output into a bounded in-memory buffer, capped both by
+// line count and total byte size (oldest entries are dropped first). It's safe for
+// concurrent readers and writers, and survives config reloads since it's owned by
+// the Deej instance rather than any particular logger configuration.
+type logRingBuffer struct {
+	mu sync.Mutex
+
+	entries  []logRingBufferEntry
+	curBytes int
+
+	maxLines int
+	maxBytes int
+}
+
+const (
+	logRingBufferDefaultMaxLines = 2000
+	logRingBufferDefaultMaxBytes = 1 << 20 // 1MB
+)
+
+// newLogRingBuffer creates a ring buffer capped at the given number of lines and bytes.
+// A value of 0 for either limit falls back to its default.
+func newLogRingBuffer(maxLines, maxBytes int) *logRingBuffer {
+	if maxLines <= 0 {
+		maxLines = logRingBufferDefaultMaxLines
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = logRingBufferDefaultMaxBytes
+	}
+
+	return &logRingBuffer{
+		entries:  make([]logRingBufferEntry, 0, maxLines),
+		maxLines: maxLines,
+		maxBytes: maxBytes,
+	}
+}
+
+// Write implements io.Writer so the buffer can be handed to zap as an additional
+// output target. Each call is treated as a single log line.
+func (b *logRingBuffer) Write(p []byte) (int, error) {
+	line := string(bytes.TrimRight(p, "\n"))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = append(b.entries, logRingBufferEntry{Time: time.Now(), Line: line})
+	b.curBytes += len(line)
+
+	for (len(b.entries) > b.maxLines || b.curBytes > b.maxBytes) && len(b.entries) > 0 {
+		b.curBytes -= len(b.entries[0].Line)
+		b.entries = b.entries[1:]
+	}
+
+	return len(p), nil
+}
+
+// Sync is a no-op to satisfy zapcore.WriteSyncer.
+func (b *logRingBuffer) Sync() error {
+	return nil
+}
+
+// Tail returns a copy of the last n buffered entries (or all of them, if n <= 0 or
+// there are fewer than n available).
+func (b *logRingBuffer) Tail(n int) []logRingBufferEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n <= 0 || n > len(b.entries) {
+		n = len(b.entries)
+	}
+
+	start := len(b.entries) - n
+	result := make([]logRingBufferEntry, n)
+	copy(result, b.entries[start:])
+
+	return result
+}
+
+// MarshalJSON-friendly snapshot of the buffer's current contents, newest last.
+func (b *logRingBuffer) JSON(n int) ([]byte, error) {
+	return json.Marshal(b.Tail(n))
+}
+
+// asWriteSyncer adapts the ring buffer to zapcore's expected interface so it can be
+// combined with deej's existing log sinks via zapcore.NewMultiWriteSyncer.
+func (b *logRingBuffer) asWriteSyncer() zapcore.WriteSyncer {
+	return b
+}
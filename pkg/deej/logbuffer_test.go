@@ -0,0 +1,46 @@
+package deej
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLogRingBufferEvictsOldestByLineCount(t *testing.T) {
+	b := newLogRingBuffer(3, 0)
+
+	for i := 0; i < 5; i++ {
+		b.Write([]byte(fmt.Sprintf("line %d", i)))
+	}
+
+	tail := b.Tail(0)
+	if len(tail) != 3 {
+		t.Fatalf("expected 3 entries after eviction, got %d", len(tail))
+	}
+
+	if tail[0].Line != "line 2" || tail[2].Line != "line 4" {
+		t.Fatalf("unexpected tail contents: %+v", tail)
+	}
+}
+
+func TestLogRingBufferEvictsOldestByByteSize(t *testing.T) {
+	b := newLogRingBuffer(100, 10)
+
+	b.Write([]byte("12345"))
+	b.Write([]byte("12345"))
+	b.Write([]byte("12345")) // pushes total past the 10-byte cap
+
+	tail := b.Tail(0)
+
+	var total int
+	for _, entry := range tail {
+		total += len(entry.Line)
+	}
+
+	if total > 10 {
+		t.Fatalf("expected buffered bytes <= 10 after eviction, got %d", total)
+	}
+
+	if len(tail) != 2 {
+		t.Fatalf("expected the oldest entry to be evicted, got %d entries", len(tail))
+	}
+}
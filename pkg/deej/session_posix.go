@@ -0,0 +1,11 @@
+//go:build !windows
+
+package deej
+
+import "fmt"
+
+// ListAppVolumes isn't implemented outside Windows yet - there's no posix
+// equivalent of the WASAPI per-session volume lookup session_windows.go uses.
+func ListAppVolumes() (map[string]float32, error) {
+	return nil, fmt.Errorf("listing per-app volumes is not supported on this platform")
+}
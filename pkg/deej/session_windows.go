@@ -279,3 +279,28 @@ func ReadAppVolumeByPID(pid int) (float32, error) {
 
 	return 0, fmt.Errorf("no audio session found for pid %d", pid)
 }
+
+// ListAppVolumes enumerates every currently active audio session (including the
+// master session) and returns a map of session key to current volume. It reuses the
+// same session finder construction as ReadMasterVolume/ReadAppVolumeByName, and is
+// intended for telemetry/status reporting rather than hot paths.
+func ListAppVolumes() (map[string]float32, error) {
+	logger := zap.NewNop().Sugar()
+	sf, err := newSessionFinder(logger)
+	if err != nil {
+		return nil, fmt.Errorf("create session finder: %w", err)
+	}
+	defer sf.Release()
+
+	sessions, err := sf.GetAllSessions()
+	if err != nil {
+		return nil, fmt.Errorf("get all sessions: %w", err)
+	}
+
+	result := make(map[string]float32, len(sessions))
+	for _, s := range sessions {
+		result[s.Key()] = s.GetVolume()
+	}
+
+	return result, nil
+}
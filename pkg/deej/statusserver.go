@@ -0,0 +1,153 @@
+package deej
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StatusServer is an opt-in, localhost-only HTTP server that exposes deej's current
+// state for debugging: recent log lines, slider/session volumes, the loaded command
+// map, and (once a CommandRunner is attached) recent command executions. It never
+// binds to anything but 127.0.0.1, regardless of what's configured.
+type StatusServer struct {
+	deej *Deej
+	logs *logRingBuffer
+
+	server *http.Server
+}
+
+// NewStatusServer creates a status server for the given Deej instance, mirroring the
+// process-wide log ring buffer. It does not start listening until Start is called.
+func NewStatusServer(d *Deej, logs *logRingBuffer) *StatusServer {
+	return &StatusServer{
+		deej: d,
+		logs: logs,
+	}
+}
+
+// Start begins serving on 127.0.0.1:<configured port> in the background. It's a
+// no-op if the status server isn't enabled in the config.
+func (s *StatusServer) Start() error {
+	if !s.deej.config.StatusServer.Enabled {
+		return nil
+	}
+
+	logger := s.deej.logger.Named("status_server")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status/logs", s.handleLogs)
+	mux.HandleFunc("/status/volumes", s.handleVolumes)
+	mux.HandleFunc("/status/commands", s.handleCommands)
+	mux.HandleFunc("/status/executions", s.handleExecutions)
+	mux.HandleFunc("/status/audit", s.handleAudit)
+
+	addr := fmt.Sprintf("127.0.0.1:%d", s.deej.config.StatusServer.Port)
+
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		logger.Infow("Starting status server", "address", addr)
+
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Warnw("Status server stopped unexpectedly", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop gracefully shuts the status server down, if it was ever started.
+func (s *StatusServer) Stop() {
+	if s.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.deej.logger.Named("status_server").Warnw("Failed to shut down status server cleanly", "error", err)
+	}
+}
+
+func (s *StatusServer) handleLogs(w http.ResponseWriter, r *http.Request) {
+	entries := s.logs.Tail(0)
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s %s\n", entry.Time.Format(time.RFC3339), entry.Line)
+		}
+
+		return
+	}
+
+	writeJSON(w, entries)
+}
+
+func (s *StatusServer) handleVolumes(w http.ResponseWriter, r *http.Request) {
+	volumes, err := ListAppVolumes()
+	if err != nil {
+		s.deej.logger.Named("status_server").Warnw("Failed to list app volumes for status endpoint", "error", err)
+		volumes = map[string]float32{}
+	}
+
+	writeJSON(w, struct {
+		SliderMapping *sliderMap         `json:"sliderMapping"`
+		Volumes       map[string]float32 `json:"volumes"`
+	}{
+		SliderMapping: s.deej.config.SliderMapping,
+		Volumes:       volumes,
+	})
+}
+
+func (s *StatusServer) handleCommands(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.deej.config.Commands)
+}
+
+func (s *StatusServer) handleExecutions(w http.ResponseWriter, r *http.Request) {
+	if s.deej.commandRunner == nil {
+		writeJSON(w, []CommandExecution{})
+		return
+	}
+
+	writeJSON(w, s.deej.commandRunner.RecentExecutions())
+}
+
+// handleAudit serves the tail of the persisted, structured audit log - the same
+// executions as /status/executions, but durable across restarts. Accepts an
+// optional ?n= query param to limit how many entries are returned (default: all
+// that remain in the active audit file).
+func (s *StatusServer) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if s.deej.auditLog == nil {
+		writeJSON(w, []CommandExecution{})
+		return
+	}
+
+	n := 0
+	if nStr := r.URL.Query().Get("n"); nStr != "" {
+		if parsed, err := strconv.Atoi(nStr); err == nil {
+			n = parsed
+		}
+	}
+
+	writeJSON(w, s.deej.auditLog.Tail(n))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
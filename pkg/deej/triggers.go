@@ -0,0 +1,190 @@
+package deej
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	muteToggleLowThreshold  = float32(0.02)
+	muteToggleHighThreshold = float32(0.98)
+)
+
+// sliderTriggerState tracks, per (index, spec), which side of the threshold (and
+// the mute-toggle extremes) the slider was last seen on, so HandleSliderValue can
+// detect edges rather than just re-evaluating a static condition every tick.
+type sliderTriggerState struct {
+	haveValue bool
+
+	aboveThreshold bool
+	atLowExtreme   bool
+	atHighExtreme  bool
+
+	holdGeneration int
+}
+
+// TriggerEvaluator watches slider value updates and fires the CommandSpecs whose
+// Trigger condition they satisfy. It complements CommandRunner.RunConfiguredCommand,
+// which remains the entry point for commands with no Trigger at all.
+type TriggerEvaluator struct {
+	runner *CommandRunner
+
+	mu     sync.Mutex
+	states map[commandKey]*sliderTriggerState
+}
+
+// NewTriggerEvaluator creates a TriggerEvaluator that fires commands through the
+// given CommandRunner.
+func NewTriggerEvaluator(runner *CommandRunner) *TriggerEvaluator {
+	return &TriggerEvaluator{
+		runner: runner,
+		states: map[commandKey]*sliderTriggerState{},
+	}
+}
+
+// HandleSliderValue is called by the slider event loop whenever slider index's
+// value changes, with the new value as a scalar between 0.0 and 1.0. It evaluates
+// every triggered command attached to that index and fires the ones whose
+// condition just became true.
+func (e *TriggerEvaluator) HandleSliderValue(index int, value float32) {
+	specs := e.runner.deej.config.Commands[index]
+
+	for specIdx, spec := range specs {
+		if spec.Trigger == nil {
+			continue
+		}
+
+		e.evaluate(index, specIdx, spec, value)
+	}
+}
+
+func (e *TriggerEvaluator) evaluate(index, specIdx int, spec CommandSpec, value float32) {
+	key := commandKey{index: index, specIdx: specIdx}
+	trigger := spec.Trigger
+
+	e.mu.Lock()
+	state, ok := e.states[key]
+	if !ok {
+		state = &sliderTriggerState{}
+		e.states[key] = state
+	}
+
+	firstSample := !state.haveValue
+	state.haveValue = true
+
+	rose, fell := thresholdCrossing(state, trigger, value)
+	muteRose, muteFell := muteToggleCrossing(state, trigger, value)
+
+	// only a genuine new edge should invalidate a pending debounce - bumping this
+	// on every tick (including incidental jitter while the slider sits past the
+	// threshold) would mean hold_ms almost never survives to fire. Edges in a
+	// direction this trigger ignores still count, so falling back before hold_ms
+	// elapses cancels a pending rising fire (and vice versa)
+	if rose || fell || muteRose || muteFell {
+		state.holdGeneration++
+	}
+	generation := state.holdGeneration
+
+	// only keep a threshold crossing if it's in a direction this trigger cares
+	// about; mute-toggle crossings always count regardless of On
+	if !matchesDirection(trigger.On, rose, fell) {
+		rose, fell = false, false
+	}
+
+	e.mu.Unlock()
+
+	if firstSample {
+		// nothing crossed on the very first reading - just establishes a baseline
+		return
+	}
+
+	reason := triggerReason(rose, fell, muteRose, muteFell)
+	if reason == "" {
+		return
+	}
+
+	if trigger.HoldMs <= 0 {
+		e.runner.fire(index, specIdx, spec, reason)
+		return
+	}
+
+	// debounce: only fire if, after hold_ms, nothing newer has superseded this
+	// crossing (another edge, or the evaluator having moved on)
+	go func() {
+		time.Sleep(time.Duration(trigger.HoldMs) * time.Millisecond)
+
+		e.mu.Lock()
+		stillCurrent := state.holdGeneration == generation
+		e.mu.Unlock()
+
+		if stillCurrent {
+			e.runner.fire(index, specIdx, spec, reason)
+		}
+	}()
+}
+
+// thresholdCrossing reports whether value just crossed trigger.Threshold, with
+// Deadband-based hysteresis so jitter around the threshold doesn't repeatedly
+// re-fire. It also updates state.aboveThreshold.
+func thresholdCrossing(state *sliderTriggerState, trigger *Trigger, value float32) (rose, fell bool) {
+	upperBound := trigger.Threshold + trigger.Deadband/2
+	lowerBound := trigger.Threshold - trigger.Deadband/2
+
+	wasAbove := state.aboveThreshold
+
+	switch {
+	case !wasAbove && value >= upperBound:
+		state.aboveThreshold = true
+		return true, false
+	case wasAbove && value <= lowerBound:
+		state.aboveThreshold = false
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// muteToggleCrossing reports whether value just crossed into the very-high or
+// very-low extreme that MuteToggle cares about. Leaving an extreme isn't reported.
+func muteToggleCrossing(state *sliderTriggerState, trigger *Trigger, value float32) (roseToHigh, fellToLow bool) {
+	if !trigger.MuteToggle {
+		return false, false
+	}
+
+	wasLow := state.atLowExtreme
+	wasHigh := state.atHighExtreme
+
+	state.atLowExtreme = value <= muteToggleLowThreshold
+	state.atHighExtreme = value >= muteToggleHighThreshold
+
+	fellToLow = !wasLow && state.atLowExtreme
+	roseToHigh = !wasHigh && state.atHighExtreme
+
+	return roseToHigh, fellToLow
+}
+
+func matchesDirection(on TriggerCondition, rose, fell bool) bool {
+	switch on {
+	case TriggerOnRising:
+		return rose
+	case TriggerOnFalling:
+		return fell
+	default: // TriggerOnAny
+		return rose || fell
+	}
+}
+
+// triggerReason decides what fired (if anything) for the audit trail: a mute-toggle
+// crossing takes priority since it's reported separately from threshold direction.
+func triggerReason(rose, fell, muteRose, muteFell bool) string {
+	switch {
+	case muteRose, muteFell:
+		return "mute_toggle"
+	case rose:
+		return "rising"
+	case fell:
+		return "falling"
+	default:
+		return ""
+	}
+}
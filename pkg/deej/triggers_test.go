@@ -0,0 +1,37 @@
+package deej
+
+import "testing"
+
+// TestHoldGenerationOnlyBumpsOnEdge guards against holdGeneration being bumped on
+// every HandleSliderValue tick: a real fader jitters by a count or two even while
+// held in place, and if that jitter invalidated the pending debounce goroutine,
+// hold_ms would never survive to fire.
+func TestHoldGenerationOnlyBumpsOnEdge(t *testing.T) {
+	spec := CommandSpec{
+		Trigger: &Trigger{On: TriggerOnRising, Threshold: 0.5, HoldMs: 50},
+	}
+
+	evaluator := NewTriggerEvaluator(&CommandRunner{})
+
+	evaluator.evaluate(0, 0, spec, 0.1) // establishes a baseline, no crossing
+	evaluator.evaluate(0, 0, spec, 0.6) // rising crossing, bumps the generation once
+
+	key := commandKey{index: 0, specIdx: 0}
+	state := evaluator.states[key]
+
+	generationAfterCrossing := state.holdGeneration
+	if generationAfterCrossing == 0 {
+		t.Fatalf("expected the initial crossing to bump holdGeneration")
+	}
+
+	// jitter at the same (already-crossed) value shouldn't invalidate the
+	// pending debounce by bumping the generation again
+	for i := 0; i < 5; i++ {
+		evaluator.evaluate(0, 0, spec, 0.6)
+	}
+
+	if state.holdGeneration != generationAfterCrossing {
+		t.Fatalf("holdGeneration changed from %d to %d due to non-edge jitter",
+			generationAfterCrossing, state.holdGeneration)
+	}
+}